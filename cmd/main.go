@@ -1,11 +1,11 @@
 package main
 
 import (
-	"context"
 	"log"
 	"net/http"
 	"os"
 
+	"go-websocket-server/internal/auth"
 	"go-websocket-server/internal/handlers"
 )
 
@@ -15,10 +15,15 @@ func main() {
 		port = "8080"
 	}
 
-	http.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
-		ctx := context.Background()
-		handlers.HandleWebSocket(ctx, w, r)
-	})
+	verifier, err := auth.NewVerifierFromEnv()
+	if err != nil {
+		log.Fatalf("failed to configure JWT verifier: %v", err)
+	}
+
+	http.HandleFunc("/ws", auth.RequireJWT(verifier, func(w http.ResponseWriter, r *http.Request) {
+		handlers.HandleWebSocket(r.Context(), w, r)
+	}))
+	http.HandleFunc("GET /sessions/{id}/cast", auth.RequireJWT(verifier, handlers.ServeSessionCast))
 
 	log.Printf("WebSocket running %s/ws", port)
 	log.Fatal(http.ListenAndServe(":"+port, nil))
@@ -0,0 +1,57 @@
+package auth
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"go-websocket-server/internal/utils"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// authorizedKeysDir is the default directory per-user authorized_keys files
+// are loaded from. It can be overridden with the AUTHORIZED_KEYS_DIR
+// environment variable.
+var authorizedKeysDir = func() string {
+	if dir := os.Getenv("AUTHORIZED_KEYS_DIR"); dir != "" {
+		return dir
+	}
+	return "authorized_keys"
+}()
+
+// Authorize checks config against the claims issued for this session: the
+// host/port/user must match exactly. When config authenticates with a
+// private key, that key must additionally match the fingerprint the claims
+// whitelist and appear in that user's authorized_keys file. Password, agent,
+// and keyboard-interactive auth carry no key material to check against the
+// token, so they are authorized on the host/port/user match alone.
+func Authorize(claims *Claims, config utils.SSHConfig) error {
+	if config.Host != claims.Host || config.Port != claims.Port || config.User != claims.User {
+		return fmt.Errorf("config does not match the host/port/user authorized by this token")
+	}
+
+	if config.PrivateKey == "" {
+		return nil
+	}
+
+	signer, err := ssh.ParsePrivateKey([]byte(config.PrivateKey))
+	if err != nil {
+		return fmt.Errorf("failed to parse private key: %w", err)
+	}
+
+	fingerprint := ssh.FingerprintSHA256(signer.PublicKey())
+	if fingerprint != claims.KeyFingerprint {
+		return fmt.Errorf("private key does not match the fingerprint authorized by this token")
+	}
+
+	keys, err := LoadAuthorizedKeys(filepath.Join(authorizedKeysDir, config.User))
+	if err != nil {
+		return fmt.Errorf("failed to load authorized_keys for user %q: %w", config.User, err)
+	}
+	if !keys.Allows(signer) {
+		return fmt.Errorf("key is not in %q's authorized_keys", config.User)
+	}
+
+	return nil
+}
@@ -0,0 +1,38 @@
+package auth
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// AuthorizedKeys is the set of public keys, indexed by their marshaled form
+// (as shown in the x/crypto/ssh server example), that a single user is
+// permitted to authenticate with.
+type AuthorizedKeys map[string]bool
+
+// LoadAuthorizedKeys parses an authorized_keys-style file at path, looping
+// over ssh.ParseAuthorizedKey as each entry is consumed.
+func LoadAuthorizedKeys(path string) (AuthorizedKeys, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read authorized_keys file: %w", err)
+	}
+
+	keys := AuthorizedKeys{}
+	for len(data) > 0 {
+		pubKey, _, _, rest, err := ssh.ParseAuthorizedKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse authorized_keys entry: %w", err)
+		}
+		keys[string(pubKey.Marshal())] = true
+		data = rest
+	}
+	return keys, nil
+}
+
+// Allows reports whether signer's public key is present in keys.
+func (keys AuthorizedKeys) Allows(signer ssh.Signer) bool {
+	return keys[string(signer.PublicKey().Marshal())]
+}
@@ -0,0 +1,19 @@
+package auth
+
+import "context"
+
+type contextKey string
+
+const claimsContextKey contextKey = "claims"
+
+// WithClaims attaches claims to ctx so the WebSocket handler can authorize
+// the session the caller is about to start against them.
+func WithClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, claimsContextKey, claims)
+}
+
+// ClaimsFromContext returns the Claims attached to ctx by RequireJWT, if any.
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(claimsContextKey).(*Claims)
+	return claims, ok
+}
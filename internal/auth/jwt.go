@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"fmt"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Claims are the custom JWT claims required to authorize a WebSocket
+// upgrade: the host/port/user this token may start an SSH session against,
+// and the SHA256 fingerprint of the public key it's allowed to authenticate
+// with.
+type Claims struct {
+	Host           string `json:"host"`
+	Port           int    `json:"port"`
+	User           string `json:"user"`
+	KeyFingerprint string `json:"keyFingerprint"`
+	jwt.RegisteredClaims
+}
+
+// Verifier validates a bearer token against a configured HS256 secret or
+// RS256 public key and returns its Claims.
+type Verifier struct {
+	alg       string
+	secret    []byte
+	publicKey *rsa.PublicKey
+}
+
+// NewVerifierFromEnv builds a Verifier from JWT_ALG (defaulting to HS256)
+// plus JWT_SECRET (for HS256) or JWT_PUBLIC_KEY_PATH (for RS256).
+func NewVerifierFromEnv() (*Verifier, error) {
+	alg := os.Getenv("JWT_ALG")
+	if alg == "" {
+		alg = "HS256"
+	}
+
+	switch alg {
+	case "HS256":
+		secret := os.Getenv("JWT_SECRET")
+		if secret == "" {
+			return nil, fmt.Errorf("JWT_SECRET is required for HS256")
+		}
+		return &Verifier{alg: alg, secret: []byte(secret)}, nil
+	case "RS256":
+		path := os.Getenv("JWT_PUBLIC_KEY_PATH")
+		if path == "" {
+			return nil, fmt.Errorf("JWT_PUBLIC_KEY_PATH is required for RS256")
+		}
+		keyData, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read JWT public key: %w", err)
+		}
+		key, err := jwt.ParseRSAPublicKeyFromPEM(keyData)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse JWT public key: %w", err)
+		}
+		return &Verifier{alg: alg, publicKey: key}, nil
+	default:
+		return nil, fmt.Errorf("unsupported JWT_ALG %q", alg)
+	}
+}
+
+// Verify parses and validates tokenString, returning its Claims.
+func (v *Verifier) Verify(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (any, error) {
+		if v.publicKey != nil {
+			return v.publicKey, nil
+		}
+		return v.secret, nil
+	}, jwt.WithValidMethods([]string{v.alg}))
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func signedToken(t *testing.T, secret []byte, claims Claims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return token
+}
+
+func TestVerifierVerifyAcceptsValidToken(t *testing.T) {
+	secret := []byte("test-secret")
+	v := &Verifier{alg: "HS256", secret: secret}
+
+	want := Claims{
+		Host:           "example.com",
+		Port:           22,
+		User:           "deploy",
+		KeyFingerprint: "SHA256:abc123",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}
+	token := signedToken(t, secret, want)
+
+	got, err := v.Verify(token)
+	if err != nil {
+		t.Fatalf("Verify() returned error: %v", err)
+	}
+	if got.Host != want.Host || got.Port != want.Port || got.User != want.User || got.KeyFingerprint != want.KeyFingerprint {
+		t.Errorf("Verify() claims = %+v, want %+v", got, want)
+	}
+}
+
+func TestVerifierVerifyRejectsWrongSecret(t *testing.T) {
+	v := &Verifier{alg: "HS256", secret: []byte("test-secret")}
+	token := signedToken(t, []byte("other-secret"), Claims{Host: "example.com"})
+
+	if _, err := v.Verify(token); err == nil {
+		t.Error("Verify() with wrong secret should have returned an error")
+	}
+}
+
+func TestVerifierVerifyRejectsExpiredToken(t *testing.T) {
+	secret := []byte("test-secret")
+	v := &Verifier{alg: "HS256", secret: secret}
+
+	claims := Claims{
+		Host: "example.com",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Hour)),
+		},
+	}
+	token := signedToken(t, secret, claims)
+
+	if _, err := v.Verify(token); err == nil {
+		t.Error("Verify() with expired token should have returned an error")
+	}
+}
+
+func TestVerifierVerifyRejectsWrongAlgorithm(t *testing.T) {
+	secret := []byte("test-secret")
+	v := &Verifier{alg: "HS256", secret: secret}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS384, Claims{Host: "example.com"}).SignedString(secret)
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+
+	if _, err := v.Verify(token); err == nil {
+		t.Error("Verify() with unexpected signing method should have returned an error")
+	}
+}
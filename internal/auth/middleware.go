@@ -0,0 +1,37 @@
+package auth
+
+import (
+	"net/http"
+	"strings"
+)
+
+// RequireJWT wraps next, requiring a valid JWT in the Authorization header
+// ("Bearer <token>") or a "token" query parameter before the WebSocket
+// upgrade is allowed to proceed. Valid claims are attached to the request
+// context for next to authorize the session against.
+func RequireJWT(verifier *Verifier, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := bearerToken(r)
+		if token == "" {
+			http.Error(w, "missing bearer token", http.StatusUnauthorized)
+			return
+		}
+
+		claims, err := verifier.Verify(token)
+		if err != nil {
+			http.Error(w, "invalid bearer token", http.StatusUnauthorized)
+			return
+		}
+
+		next(w, r.WithContext(WithClaims(r.Context(), claims)))
+	}
+}
+
+func bearerToken(r *http.Request) string {
+	if header := r.Header.Get("Authorization"); header != "" {
+		if after, ok := strings.CutPrefix(header, "Bearer "); ok {
+			return after
+		}
+	}
+	return r.URL.Query().Get("token")
+}
@@ -2,17 +2,74 @@ package clients
 
 import (
 	"go-websocket-server/internal/services"
+	"go-websocket-server/internal/utils"
 	"sync"
-
-	"github.com/gorilla/websocket"
+	"time"
 )
 
 type Client struct {
-	Conn          *websocket.Conn
+	Conn          *utils.SafeConn
 	SSHClient     *services.SSHSession
 	IsConnected   bool
 	CommandBuffer string
 	Mu            sync.Mutex
+	Channels      map[string]services.Channel
+	ChannelsMu    sync.Mutex
+
+	SourceIP      string
+	ConnectedAt   time.Time
+	InputLimiter  *utils.RateLimiter
+	OutputLimiter *utils.RateLimiter
+
+	activityMu   sync.Mutex
+	lastActivity time.Time
+}
+
+// Touch records that activity just happened on this client, resetting its
+// idle timer. HandleWebSocket calls it for messages from the browser (input
+// and channel traffic); shell output does not reset the timer.
+func (c *Client) Touch() {
+	c.activityMu.Lock()
+	defer c.activityMu.Unlock()
+	c.lastActivity = time.Now()
+}
+
+// IdleFor returns how long it has been since the last recorded activity.
+func (c *Client) IdleFor() time.Duration {
+	c.activityMu.Lock()
+	defer c.activityMu.Unlock()
+	if c.lastActivity.IsZero() {
+		return 0
+	}
+	return time.Since(c.lastActivity)
+}
+
+// AddChannel registers a multiplexed channel (port forward, SFTP subsystem)
+// under id so later WSMessages carrying that ChannelID can be routed to it.
+func (c *Client) AddChannel(id string, ch services.Channel) {
+	c.ChannelsMu.Lock()
+	defer c.ChannelsMu.Unlock()
+	if c.Channels == nil {
+		c.Channels = make(map[string]services.Channel)
+	}
+	c.Channels[id] = ch
+}
+
+// GetChannel returns the channel registered under id, or nil if none exists.
+func (c *Client) GetChannel(id string) services.Channel {
+	c.ChannelsMu.Lock()
+	defer c.ChannelsMu.Unlock()
+	return c.Channels[id]
+}
+
+// RemoveChannel closes and forgets the channel registered under id, if any.
+func (c *Client) RemoveChannel(id string) {
+	c.ChannelsMu.Lock()
+	defer c.ChannelsMu.Unlock()
+	if ch, exists := c.Channels[id]; exists {
+		ch.Close()
+		delete(c.Channels, id)
+	}
 }
 
 var (
@@ -51,8 +108,17 @@ func CleanupConnection(clientID string) {
 		if client.SSHClient != nil {
 			client.SSHClient.Close()
 		}
+		client.ChannelsMu.Lock()
+		for id, ch := range client.Channels {
+			ch.Close()
+			delete(client.Channels, id)
+		}
+		client.ChannelsMu.Unlock()
 		client.Conn.Close()
 		delete(clients, clientID)
+		if client.SourceIP != "" {
+			ReleaseSession(client.SourceIP)
+		}
 	}
 	mu.Unlock()
 }
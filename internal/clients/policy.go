@@ -0,0 +1,86 @@
+package clients
+
+import (
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Policy bounds how much one browser client may do: input/output throughput,
+// how long a session may sit idle, how long any one session may live, and
+// how many concurrent sessions a single source IP may hold open.
+type Policy struct {
+	InputBytesPerSecond  int
+	OutputBytesPerSecond int
+	IdleTimeout          time.Duration
+	MaxSessionLifetime   time.Duration
+	MaxSessionsPerIP     int
+}
+
+// PolicyFromEnv builds a Policy from environment variables, falling back to
+// conservative defaults for anything unset.
+func PolicyFromEnv() Policy {
+	return Policy{
+		InputBytesPerSecond:  envInt("POLICY_INPUT_BYTES_PER_SECOND", 8*1024),
+		OutputBytesPerSecond: envInt("POLICY_OUTPUT_BYTES_PER_SECOND", 64*1024),
+		IdleTimeout:          envDuration("POLICY_IDLE_TIMEOUT", 10*time.Minute),
+		MaxSessionLifetime:   envDuration("POLICY_MAX_SESSION_LIFETIME", 4*time.Hour),
+		MaxSessionsPerIP:     envInt("POLICY_MAX_SESSIONS_PER_IP", 5),
+	}
+}
+
+func envInt(key string, fallback int) int {
+	if v := os.Getenv(key); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			return n
+		}
+	}
+	return fallback
+}
+
+func envDuration(key string, fallback time.Duration) time.Duration {
+	if v := os.Getenv(key); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			return d
+		}
+	}
+	return fallback
+}
+
+var (
+	ipSessions = make(map[string]int)
+	ipMu       sync.Mutex
+)
+
+// CanAddSession reports whether sourceIP has room for one more concurrent
+// session under maxPerIP, and reserves the slot if so. A non-positive
+// maxPerIP disables the limit.
+func CanAddSession(sourceIP string, maxPerIP int) bool {
+	if maxPerIP <= 0 {
+		return true
+	}
+
+	ipMu.Lock()
+	defer ipMu.Unlock()
+
+	if ipSessions[sourceIP] >= maxPerIP {
+		return false
+	}
+	ipSessions[sourceIP]++
+	return true
+}
+
+// ReleaseSession frees the concurrent-session slot reserved for sourceIP by
+// CanAddSession.
+func ReleaseSession(sourceIP string) {
+	ipMu.Lock()
+	defer ipMu.Unlock()
+
+	if ipSessions[sourceIP] > 0 {
+		ipSessions[sourceIP]--
+		if ipSessions[sourceIP] == 0 {
+			delete(ipSessions, sourceIP)
+		}
+	}
+}
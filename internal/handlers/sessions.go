@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"net/http"
+	"os"
+
+	"go-websocket-server/internal/services"
+	"go-websocket-server/internal/utils"
+)
+
+// ServeSessionCast streams the asciinema recording for the session identified
+// by the "id" path value, if one was recorded.
+//
+// Example usage:
+//
+//	http.HandleFunc("GET /sessions/{id}/cast", auth.RequireJWT(verifier, handlers.ServeSessionCast))
+func ServeSessionCast(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		appErr := utils.NewAppError("MISSING_SESSION_ID", "Session id not provided", nil)
+		appErr.Log()
+		http.Error(w, appErr.Message, http.StatusBadRequest)
+		return
+	}
+
+	dir := os.Getenv("RECORDINGS_DIR")
+	if dir == "" {
+		dir = "recordings"
+	}
+
+	path := services.CastFilePath(dir, id)
+	if _, err := os.Stat(path); err != nil {
+		appErr := utils.NewAppError("RECORDING_NOT_FOUND", "No recording found for session", err)
+		appErr.Log()
+		http.Error(w, appErr.Message, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-asciicast")
+	http.ServeFile(w, r, path)
+}
@@ -2,9 +2,13 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
 	"log"
+	"net"
 	"net/http"
+	"time"
 
+	"go-websocket-server/internal/auth"
 	"go-websocket-server/internal/clients"
 	"go-websocket-server/internal/services"
 	"go-websocket-server/internal/utils"
@@ -33,28 +37,52 @@ var upgrader = websocket.Upgrader{
 //
 //	http.HandleFunc("/websocket", HandleWebSocket)
 func HandleWebSocket(ctx context.Context, w http.ResponseWriter, r *http.Request) {
-	conn, err := upgrader.Upgrade(w, r, nil)
+	wsConn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		appErr := utils.NewAppError("WS_UPGRADE_FAILED", "Failed to upgrade to WebSocket", err)
 		appErr.Log()
 		http.Error(w, appErr.Message, http.StatusInternalServerError)
 		return
 	}
-	defer conn.Close()
+	defer wsConn.Close()
+
+	// conn serializes writes across every goroutine that ends up sharing this
+	// connection (shell output, the policy monitor, multiplexed channels):
+	// gorilla/websocket allows only one writer at a time.
+	conn := utils.NewSafeConn(wsConn)
 
 	clientID := r.Header.Get("Sec-WebSocket-Key")
 	if clientID == "" {
 		appErr := utils.NewAppError("MISSING_CLIENT_ID", "Sec-WebSocket-Key not provided", nil)
 		appErr.Log()
-		conn.WriteJSON(utils.WSMessage{Type: "error", Content: appErr.Message})
+		conn.WriteJSON(utils.WSMessage{Type: "error", Code: appErr.Code, Content: appErr.Message})
+		return
+	}
+
+	sourceIP := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		sourceIP = host
+	}
+
+	policy := clients.PolicyFromEnv()
+	if !clients.CanAddSession(sourceIP, policy.MaxSessionsPerIP) {
+		appErr := utils.NewAppError("TOO_MANY_SESSIONS", "Too many concurrent sessions for this address", nil)
+		appErr.Log()
+		conn.WriteJSON(utils.WSMessage{Type: "policy_violation", Code: appErr.Code, Content: appErr.Message})
 		return
 	}
 
 	client := &clients.Client{
-		Conn:        conn,
-		IsConnected: true,
+		Conn:          conn,
+		IsConnected:   true,
+		SourceIP:      sourceIP,
+		ConnectedAt:   time.Now(),
+		InputLimiter:  utils.NewRateLimiter(policy.InputBytesPerSecond),
+		OutputLimiter: utils.NewRateLimiter(policy.OutputBytesPerSecond),
 	}
+	client.Touch()
 	clients.AddClient(clientID, client)
+	defer clients.CleanupConnection(clientID)
 
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
@@ -63,22 +91,45 @@ func HandleWebSocket(ctx context.Context, w http.ResponseWriter, r *http.Request
 	if err := conn.ReadJSON(&msg); err != nil {
 		appErr := utils.NewAppError("WS_READ_FAILED", "Failed to read initial WebSocket message", err)
 		appErr.Log()
-		conn.WriteJSON(utils.WSMessage{Type: "error", Content: appErr.Message})
+		conn.WriteJSON(utils.WSMessage{Type: "error", Code: appErr.Code, Content: appErr.Message})
+		return
+	}
+
+	claims, ok := auth.ClaimsFromContext(ctx)
+	if !ok {
+		appErr := utils.NewAppError("AUTH_CONTEXT_MISSING", "Missing authentication context", nil)
+		appErr.Log()
+		conn.WriteJSON(utils.WSMessage{Type: "auth_denied", Code: appErr.Code, Content: appErr.Message})
 		return
 	}
 
-	session, err := services.StartSSHSession(ctx, msg.Content, conn)
+	var config utils.SSHConfig
+	if err := json.Unmarshal([]byte(msg.Content), &config); err != nil {
+		appErr := utils.NewAppError("INVALID_CONFIG", "Invalid configuration format", err)
+		appErr.Log()
+		conn.WriteJSON(utils.WSMessage{Type: "error", Code: appErr.Code, Content: appErr.Message})
+		return
+	}
+
+	if err := auth.Authorize(claims, config); err != nil {
+		appErr := utils.NewAppError("AUTH_DENIED", err.Error(), err)
+		appErr.Log()
+		conn.WriteJSON(utils.WSMessage{Type: "auth_denied", Code: appErr.Code, Content: appErr.Message})
+		return
+	}
+
+	session, err := services.StartSSHSession(ctx, clientID, msg.Content, conn)
 	if err != nil {
 		appErr := utils.NewAppError("SSH_SESSION_FAILED", "Failed to start SSH session", err)
 		appErr.Log()
-		conn.WriteJSON(utils.WSMessage{Type: "error", Content: appErr.Message})
+		conn.WriteJSON(utils.WSMessage{Type: "error", Code: appErr.Code, Content: appErr.Message})
 		return
 	}
-	defer session.Close()
-
+	session.SetLimiters(client.InputLimiter, client.OutputLimiter)
 	client.SSHClient = session
 
 	go session.HandleOutput(ctx)
+	go monitorPolicy(ctx, cancel, clientID, client, policy, conn)
 
 	for {
 		select {
@@ -92,12 +143,13 @@ func HandleWebSocket(ctx context.Context, w http.ResponseWriter, r *http.Request
 			if err := conn.ReadJSON(&msg); err != nil {
 				appErr := utils.NewAppError("WS_READ_FAILED", "Failed to read WebSocket message", err)
 				appErr.Log()
-				conn.WriteJSON(utils.WSMessage{Type: "error", Content: appErr.Message})
+				conn.WriteJSON(utils.WSMessage{Type: "error", Code: appErr.Code, Content: appErr.Message})
 				return
 			}
 
 			switch msg.Type {
 			case "input":
+				client.Touch()
 				client.Mu.Lock()
 				client.CommandBuffer += msg.Content
 
@@ -106,20 +158,99 @@ func HandleWebSocket(ctx context.Context, w http.ResponseWriter, r *http.Request
 					client.Mu.Unlock()
 					return
 				}
-				session.SendInput(msg.Content)
+				if !session.SendInput(msg.Content) {
+					conn.WriteJSON(utils.WSMessage{Type: "policy_violation", Code: "INPUT_RATE_LIMITED", Content: "input rate limit exceeded"})
+				}
 				client.Mu.Unlock()
 			case "resize":
 				err := session.ResizeTerminal(msg.Rows, msg.Cols)
 				if err != nil {
 					appErr := utils.NewAppError("RESIZE_FAILED", "Failed to resize terminal", err)
 					appErr.Log()
-					conn.WriteJSON(utils.WSMessage{Type: "error", Content: appErr.Message})
+					conn.WriteJSON(utils.WSMessage{Type: "error", Code: appErr.Code, Content: appErr.Message})
 				}
+			case "channel_open":
+				client.Touch()
+				openChannel(client, session, msg, conn)
+			case "channel_data", "sftp":
+				client.Touch()
+				if ch := client.GetChannel(msg.ChannelID); ch != nil {
+					ch.HandleMessage(msg)
+				}
+			case "channel_close":
+				client.Touch()
+				client.RemoveChannel(msg.ChannelID)
 			default:
 				appErr := utils.NewAppError("UNKNOWN_MESSAGE_TYPE", "Unknown message type received", nil)
 				appErr.Log()
-				conn.WriteJSON(utils.WSMessage{Type: "error", Content: appErr.Message})
+				conn.WriteJSON(utils.WSMessage{Type: "error", Code: appErr.Code, Content: appErr.Message})
+			}
+		}
+	}
+}
+
+// monitorPolicy periodically checks client against policy's idle timeout and
+// maximum session lifetime. When either is exceeded it emits a
+// "policy_violation" message, tears the connection down via
+// CleanupConnection, and cancels ctx so the read loop unwinds.
+func monitorPolicy(ctx context.Context, cancel context.CancelFunc, clientID string, client *clients.Client, policy clients.Policy, conn *utils.SafeConn) {
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reason, code := "", ""
+			switch {
+			case policy.IdleTimeout > 0 && client.IdleFor() > policy.IdleTimeout:
+				reason, code = "idle timeout exceeded", "IDLE_TIMEOUT"
+			case policy.MaxSessionLifetime > 0 && time.Since(client.ConnectedAt) > policy.MaxSessionLifetime:
+				reason, code = "maximum session lifetime exceeded", "MAX_SESSION_LIFETIME"
 			}
+			if reason == "" {
+				continue
+			}
+
+			conn.WriteJSON(utils.WSMessage{Type: "policy_violation", Code: code, Content: reason})
+			clients.CleanupConnection(clientID)
+			cancel()
+			return
 		}
 	}
 }
+
+// openChannel opens a new multiplexed channel alongside the interactive
+// shell and registers it on client, keyed by msg.ChannelID. The kind of
+// channel to open is selected by msg.Op: "direct-tcpip" for a local port
+// forward, "tcpip-forward" for a remote listener, or "sftp" for an SFTP
+// subsystem.
+func openChannel(client *clients.Client, session *services.SSHSession, msg utils.WSMessage, conn *utils.SafeConn) {
+	var ch services.Channel
+	var err error
+
+	limits := services.ChannelLimits{Input: client.InputLimiter, Output: client.OutputLimiter}
+
+	switch msg.Op {
+	case "direct-tcpip":
+		ch, err = services.OpenDirectTCPIP(session.Client(), msg.ChannelID, msg.TargetHost, msg.TargetPort, conn, limits)
+	case "tcpip-forward":
+		ch, err = services.OpenRemoteForward(session.Client(), msg.ChannelID, msg.BindHost, msg.BindPort, conn, limits, func(sub services.Channel) {
+			client.AddChannel(sub.ID(), sub)
+		})
+	case "sftp":
+		ch, err = services.OpenSFTPChannel(session.Client(), msg.ChannelID, conn, limits)
+	default:
+		err = utils.NewAppError("UNKNOWN_CHANNEL_OP", "Unknown channel op received", nil)
+	}
+
+	if err != nil {
+		appErr := utils.NewAppError("CHANNEL_OPEN_FAILED", "Failed to open channel", err)
+		appErr.Log()
+		conn.WriteJSON(utils.WSMessage{Type: "error", Code: appErr.Code, ChannelID: msg.ChannelID, Content: appErr.Message})
+		return
+	}
+
+	client.AddChannel(msg.ChannelID, ch)
+}
@@ -0,0 +1,21 @@
+package services
+
+import "go-websocket-server/internal/utils"
+
+// Channel is a single multiplexed stream riding on top of one WebSocket
+// connection alongside the interactive shell, addressed by ChannelID in
+// every WSMessage once it has been opened. Port forwards and the SFTP
+// subsystem each implement it.
+type Channel interface {
+	ID() string
+	HandleMessage(msg utils.WSMessage)
+	Close()
+}
+
+// ChannelLimits bundles the same per-client rate limiters the interactive
+// shell is policed with, so multiplexed channels (port forwards, SFTP)
+// count their bytes against the same budget instead of bypassing it.
+type ChannelLimits struct {
+	Input  *utils.RateLimiter
+	Output *utils.RateLimiter
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"encoding/base64"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"go-websocket-server/internal/utils"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// ForwardChannel pipes bytes between a "direct-tcpip" local port forward
+// dialed through the SSH client and the browser, framed as base64 inside
+// WSMessages carrying this channel's ChannelID.
+type ForwardChannel struct {
+	id     string
+	conn   *utils.SafeConn
+	nc     net.Conn
+	limits ChannelLimits
+	once   sync.Once
+	done   chan struct{}
+}
+
+// OpenDirectTCPIP dials targetHost:targetPort through client's SSH connection
+// (an ssh "direct-tcpip" channel) and starts relaying its bytes to conn as
+// "channel_data" WSMessages on channelID, policed by limits.
+func OpenDirectTCPIP(client *ssh.Client, channelID, targetHost string, targetPort int, conn *utils.SafeConn, limits ChannelLimits) (*ForwardChannel, error) {
+	addr := fmt.Sprintf("%s:%d", targetHost, targetPort)
+	nc, err := client.Dial("tcp", addr)
+	if err != nil {
+		return nil, utils.NewAppError("FORWARD_DIAL_FAILED", "Failed to dial forwarded address", err)
+	}
+
+	return newForwardChannel(channelID, nc, conn, limits), nil
+}
+
+// newForwardChannel wraps an already-established connection (dialed locally
+// or accepted from a remote listener) in a ForwardChannel and starts relaying
+// its bytes to the browser.
+func newForwardChannel(channelID string, nc net.Conn, conn *utils.SafeConn, limits ChannelLimits) *ForwardChannel {
+	f := &ForwardChannel{id: channelID, conn: conn, nc: nc, limits: limits, done: make(chan struct{})}
+	go f.pump()
+	return f
+}
+
+// ID returns the channel ID this forward is addressed by.
+func (f *ForwardChannel) ID() string {
+	return f.id
+}
+
+// pump copies bytes arriving from the forwarded connection back to the
+// browser as base64-encoded "channel_data" messages.
+func (f *ForwardChannel) pump() {
+	buf := make([]byte, 4096)
+	for {
+		n, err := f.nc.Read(buf)
+		if n > 0 {
+			f.limits.Output.Wait(n)
+			f.conn.WriteJSONWithDeadline(time.Now().Add(outputWriteTimeout), utils.WSMessage{
+				Type:      "channel_data",
+				ChannelID: f.id,
+				Op:        "data",
+				Data:      base64.StdEncoding.EncodeToString(buf[:n]),
+			})
+		}
+		if err != nil {
+			f.Close()
+			return
+		}
+	}
+}
+
+// HandleMessage writes data the browser sent for this channel to the
+// forwarded connection, or closes it on a "close" op.
+func (f *ForwardChannel) HandleMessage(msg utils.WSMessage) {
+	switch msg.Op {
+	case "data":
+		data, err := base64.StdEncoding.DecodeString(msg.Data)
+		if err != nil {
+			log.Printf("Error decoding forward data: %v", err)
+			return
+		}
+		if !f.limits.Input.Allow(len(data)) {
+			f.conn.WriteJSON(utils.WSMessage{Type: "policy_violation", Code: "CHANNEL_INPUT_RATE_LIMITED", ChannelID: f.id, Content: "channel input rate limit exceeded"})
+			return
+		}
+		if _, err := f.nc.Write(data); err != nil {
+			log.Printf("Error writing forward data: %v", err)
+		}
+	case "close":
+		f.Close()
+	}
+}
+
+// Close closes the forwarded connection. Safe to call more than once.
+func (f *ForwardChannel) Close() {
+	f.once.Do(func() {
+		close(f.done)
+		f.nc.Close()
+	})
+}
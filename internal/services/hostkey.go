@@ -0,0 +1,105 @@
+package services
+
+import (
+	"errors"
+	"net"
+	"os"
+
+	"go-websocket-server/internal/utils"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// HostKeyVerifier builds an ssh.HostKeyCallback backed by a known_hosts file.
+// When StrictHostKeyChecking is disabled, a host key that is absent from the
+// known_hosts file is not rejected outright: instead it is surfaced to the
+// browser client as a "hostkey_prompt" WSMessage carrying the key's SHA256
+// fingerprint, and the dial blocks until the client answers with
+// "hostkey_accept" or "hostkey_reject" (trust-on-first-use). Accepted keys
+// are appended to the known_hosts file so future dials verify silently.
+type HostKeyVerifier struct {
+	KnownHostsPath string
+	Strict         bool
+	conn           *utils.SafeConn
+}
+
+// NewHostKeyVerifier creates a HostKeyVerifier that persists accepted host
+// keys to knownHostsPath and prompts over conn when a key is unknown.
+func NewHostKeyVerifier(knownHostsPath string, strict bool, conn *utils.SafeConn) *HostKeyVerifier {
+	return &HostKeyVerifier{
+		KnownHostsPath: knownHostsPath,
+		Strict:         strict,
+		conn:           conn,
+	}
+}
+
+// Callback returns the ssh.HostKeyCallback to use in an ssh.ClientConfig.
+func (v *HostKeyVerifier) Callback() (ssh.HostKeyCallback, error) {
+	if _, err := os.OpenFile(v.KnownHostsPath, os.O_CREATE|os.O_RDONLY, 0o600); err != nil {
+		return nil, utils.NewAppError("KNOWN_HOSTS_OPEN_FAILED", "Failed to open known_hosts file", err)
+	}
+
+	base, err := knownhosts.New(v.KnownHostsPath)
+	if err != nil {
+		return nil, utils.NewAppError("KNOWN_HOSTS_PARSE_FAILED", "Failed to parse known_hosts file", err)
+	}
+
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		err := base(hostname, remote, key)
+		if err == nil {
+			return nil
+		}
+
+		var keyErr *knownhosts.KeyError
+		if !errors.As(err, &keyErr) || len(keyErr.Want) != 0 {
+			// Either a non-knownhosts error, or the host is known under a
+			// different key: this is a real mismatch, never TOFU through it.
+			return err
+		}
+
+		if v.Strict {
+			return err
+		}
+
+		return v.promptAndPersist(hostname, key)
+	}, nil
+}
+
+// promptAndPersist surfaces an unknown host key to the browser client and
+// blocks until it responds. On acceptance the key is appended to the
+// known_hosts file so subsequent dials verify without prompting again.
+func (v *HostKeyVerifier) promptAndPersist(hostname string, key ssh.PublicKey) error {
+	fingerprint := ssh.FingerprintSHA256(key)
+
+	if err := v.conn.WriteJSON(utils.WSMessage{
+		Type:        "hostkey_prompt",
+		Host:        hostname,
+		Fingerprint: fingerprint,
+	}); err != nil {
+		return utils.NewAppError("HOSTKEY_PROMPT_FAILED", "Failed to send host key prompt", err)
+	}
+
+	var reply utils.WSMessage
+	if err := v.conn.ReadJSON(&reply); err != nil {
+		return utils.NewAppError("HOSTKEY_REPLY_FAILED", "Failed to read host key response", err)
+	}
+
+	switch reply.Type {
+	case "hostkey_accept":
+		line := knownhosts.Line([]string{hostname}, key)
+		f, err := os.OpenFile(v.KnownHostsPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+		if err != nil {
+			return utils.NewAppError("KNOWN_HOSTS_WRITE_FAILED", "Failed to persist accepted host key", err)
+		}
+		defer f.Close()
+		if _, err := f.WriteString(line + "\n"); err != nil {
+			return utils.NewAppError("KNOWN_HOSTS_WRITE_FAILED", "Failed to persist accepted host key", err)
+		}
+		return nil
+	case "hostkey_reject":
+		return utils.NewAppError("HOSTKEY_REJECTED", "Host key rejected by client", nil)
+	default:
+		return utils.NewAppError("HOSTKEY_REPLY_INVALID", "Expected hostkey_accept or hostkey_reject", nil)
+	}
+}
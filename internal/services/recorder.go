@@ -0,0 +1,113 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go-websocket-server/internal/utils"
+)
+
+// SessionRecorder captures PTY output (and optionally input) from an
+// SSHSession into an asciinema v2 cast file, giving operators a replayable
+// audit trail of what happened in a web SSH session.
+type SessionRecorder struct {
+	file     *os.File
+	start    time.Time
+	recordIn bool
+	mu       sync.Mutex
+}
+
+type castHeader struct {
+	Version   int   `json:"version"`
+	Width     int   `json:"width"`
+	Height    int   `json:"height"`
+	Timestamp int64 `json:"timestamp"`
+}
+
+// NewSessionRecorder creates the cast file for clientID under dir, rotating
+// any previous recording for the same client out of the way, and writes the
+// asciinema v2 header line.
+func NewSessionRecorder(dir, clientID string, cols, rows int, recordInput bool) (*SessionRecorder, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, utils.NewAppError("RECORDING_DIR_FAILED", "Failed to create recordings directory", err)
+	}
+
+	path := CastFilePath(dir, clientID)
+	if _, err := os.Stat(path); err == nil {
+		rotated := filepath.Join(dir, fmt.Sprintf("%s-%d.cast", clientID, time.Now().UnixNano()))
+		if err := os.Rename(path, rotated); err != nil {
+			return nil, utils.NewAppError("RECORDING_ROTATE_FAILED", "Failed to rotate previous recording", err)
+		}
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, utils.NewAppError("RECORDING_CREATE_FAILED", "Failed to create recording file", err)
+	}
+
+	header, err := json.Marshal(castHeader{Version: 2, Width: cols, Height: rows, Timestamp: time.Now().Unix()})
+	if err != nil {
+		f.Close()
+		return nil, utils.NewAppError("RECORDING_HEADER_FAILED", "Failed to encode recording header", err)
+	}
+	if _, err := f.Write(append(header, '\n')); err != nil {
+		f.Close()
+		return nil, utils.NewAppError("RECORDING_HEADER_FAILED", "Failed to write recording header", err)
+	}
+
+	return &SessionRecorder{file: f, start: time.Now(), recordIn: recordInput}, nil
+}
+
+// CastFilePath returns the path a clientID's recording is (or would be) stored
+// at. clientID is passed through sanitizePathComponent, so the raw client ID
+// and its sanitized form map to the same file.
+func CastFilePath(dir, clientID string) string {
+	return filepath.Join(dir, sanitizePathComponent(clientID)+".cast")
+}
+
+func (r *SessionRecorder) writeEvent(code, data string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return
+	}
+	event, err := json.Marshal([]any{time.Since(r.start).Seconds(), code, data})
+	if err != nil {
+		return
+	}
+	r.file.Write(append(event, '\n'))
+}
+
+// WriteOutput records a PTY output chunk as an "o" event.
+func (r *SessionRecorder) WriteOutput(data string) {
+	r.writeEvent("o", data)
+}
+
+// WriteInput records terminal input as an "i" event, when input recording is enabled.
+func (r *SessionRecorder) WriteInput(data string) {
+	if !r.recordIn {
+		return
+	}
+	r.writeEvent("i", data)
+}
+
+// WriteResize records a terminal resize as an "r" event.
+func (r *SessionRecorder) WriteResize(cols, rows int) {
+	r.writeEvent("r", fmt.Sprintf("%dx%d", cols, rows))
+}
+
+// Close flushes and closes the underlying cast file.
+func (r *SessionRecorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"fmt"
+	"net"
+	"sync"
+
+	"go-websocket-server/internal/utils"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// RemoteForwardChannel listens on the remote SSH server (an ssh
+// "tcpip-forward" request) and hands each accepted connection to onAccept as
+// its own ForwardChannel, addressed by a sub-channel ID of the form
+// "<channelID>:<n>" so the caller can register it in the client's channel map.
+type RemoteForwardChannel struct {
+	id       string
+	listener net.Listener
+	conn     *utils.SafeConn
+	limits   ChannelLimits
+	once     sync.Once
+	done     chan struct{}
+}
+
+// OpenRemoteForward asks the SSH server to listen on bindHost:bindPort and
+// relays every accepted connection to the browser, policed by limits.
+// onAccept is invoked with the resulting sub-channel so it can be tracked
+// alongside this one.
+func OpenRemoteForward(client *ssh.Client, channelID, bindHost string, bindPort int, conn *utils.SafeConn, limits ChannelLimits, onAccept func(Channel)) (*RemoteForwardChannel, error) {
+	addr := fmt.Sprintf("%s:%d", bindHost, bindPort)
+	listener, err := client.Listen("tcp", addr)
+	if err != nil {
+		return nil, utils.NewAppError("REMOTE_FORWARD_FAILED", "Failed to listen on remote address", err)
+	}
+
+	r := &RemoteForwardChannel{id: channelID, listener: listener, conn: conn, limits: limits, done: make(chan struct{})}
+	go r.acceptLoop(onAccept)
+	return r, nil
+}
+
+// acceptLoop accepts remote connections until the listener is closed,
+// handing each one to onAccept as a new ForwardChannel.
+func (r *RemoteForwardChannel) acceptLoop(onAccept func(Channel)) {
+	n := 0
+	for {
+		nc, err := r.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		n++
+		subID := fmt.Sprintf("%s:%d", r.id, n)
+		r.conn.WriteJSON(utils.WSMessage{Type: "channel_open", ChannelID: subID, Op: "tcpip-forward-accept"})
+		onAccept(newForwardChannel(subID, nc, r.conn, r.limits))
+	}
+}
+
+// ID returns the channel ID this remote forward is addressed by.
+func (r *RemoteForwardChannel) ID() string {
+	return r.id
+}
+
+// HandleMessage closes the remote listener on a "close" op; data for
+// individual accepted connections is addressed to their own sub-channel IDs.
+func (r *RemoteForwardChannel) HandleMessage(msg utils.WSMessage) {
+	if msg.Op == "close" {
+		r.Close()
+	}
+}
+
+// Close stops accepting new remote connections. Safe to call more than once.
+func (r *RemoteForwardChannel) Close() {
+	r.once.Do(func() {
+		close(r.done)
+		r.listener.Close()
+	})
+}
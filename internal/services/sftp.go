@@ -0,0 +1,157 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"go-websocket-server/internal/utils"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// SFTPChannel exposes a small subset of the SFTP subsystem (ls, get, put,
+// stat) over a single multiplexed WebSocket channel.
+type SFTPChannel struct {
+	id     string
+	client *sftp.Client
+	conn   *utils.SafeConn
+	limits ChannelLimits
+}
+
+// sftpFileInfo is the JSON shape an "ls" or "stat" op replies with.
+type sftpFileInfo struct {
+	Name  string `json:"name"`
+	Size  int64  `json:"size"`
+	Mode  string `json:"mode"`
+	IsDir bool   `json:"isDir"`
+}
+
+// OpenSFTPChannel starts the SFTP subsystem over the SSH client and returns a
+// channel that dispatches ls/get/put/stat ops sent for channelID.
+func OpenSFTPChannel(sshClient *ssh.Client, channelID string, conn *utils.SafeConn, limits ChannelLimits) (*SFTPChannel, error) {
+	client, err := sftp.NewClient(sshClient)
+	if err != nil {
+		return nil, utils.NewAppError("SFTP_START_FAILED", "Failed to start SFTP subsystem", err)
+	}
+	return &SFTPChannel{id: channelID, client: client, conn: conn, limits: limits}, nil
+}
+
+// ID returns the channel ID this SFTP subsystem is addressed by.
+func (c *SFTPChannel) ID() string {
+	return c.id
+}
+
+// HandleMessage dispatches a single SFTP operation requested for this
+// channel and writes its result back as an "sftp_result" WSMessage.
+func (c *SFTPChannel) HandleMessage(msg utils.WSMessage) {
+	switch msg.Op {
+	case "ls":
+		c.handleLs(msg.Path)
+	case "stat":
+		c.handleStat(msg.Path)
+	case "get":
+		c.handleGet(msg.Path)
+	case "put":
+		c.handlePut(msg.Path, msg.Data)
+	default:
+		c.reply(msg.Path, utils.NewAppError("SFTP_UNKNOWN_OP", fmt.Sprintf("Unknown SFTP op %q", msg.Op), nil))
+	}
+}
+
+func (c *SFTPChannel) handleLs(path string) {
+	entries, err := c.client.ReadDir(path)
+	if err != nil {
+		c.reply(path, utils.NewAppError("SFTP_LS_FAILED", "Failed to list directory", err))
+		return
+	}
+
+	infos := make([]sftpFileInfo, 0, len(entries))
+	for _, e := range entries {
+		infos = append(infos, sftpFileInfo{Name: e.Name(), Size: e.Size(), Mode: e.Mode().String(), IsDir: e.IsDir()})
+	}
+	c.replyOK(path, "ls", infos)
+}
+
+func (c *SFTPChannel) handleStat(path string) {
+	info, err := c.client.Stat(path)
+	if err != nil {
+		c.reply(path, utils.NewAppError("SFTP_STAT_FAILED", "Failed to stat path", err))
+		return
+	}
+	c.replyOK(path, "stat", sftpFileInfo{Name: info.Name(), Size: info.Size(), Mode: info.Mode().String(), IsDir: info.IsDir()})
+}
+
+func (c *SFTPChannel) handleGet(path string) {
+	f, err := c.client.Open(path)
+	if err != nil {
+		c.reply(path, utils.NewAppError("SFTP_GET_FAILED", "Failed to open remote file", err))
+		return
+	}
+	defer f.Close()
+
+	content, err := io.ReadAll(f)
+	if err != nil {
+		c.reply(path, utils.NewAppError("SFTP_GET_FAILED", "Failed to read remote file", err))
+		return
+	}
+
+	c.limits.Output.Wait(len(content))
+	c.conn.WriteJSON(utils.WSMessage{
+		Type:      "sftp_result",
+		ChannelID: c.id,
+		Op:        "get",
+		Path:      path,
+		Data:      base64.StdEncoding.EncodeToString(content),
+	})
+}
+
+func (c *SFTPChannel) handlePut(path, data string) {
+	content, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		c.reply(path, utils.NewAppError("SFTP_PUT_FAILED", "Failed to decode file data", err))
+		return
+	}
+
+	if !c.limits.Input.Allow(len(content)) {
+		c.reply(path, utils.NewAppError("SFTP_PUT_RATE_LIMITED", "SFTP input rate limit exceeded", nil))
+		return
+	}
+
+	f, err := c.client.Create(path)
+	if err != nil {
+		c.reply(path, utils.NewAppError("SFTP_PUT_FAILED", "Failed to create remote file", err))
+		return
+	}
+	defer f.Close()
+
+	if _, err := f.Write(content); err != nil {
+		c.reply(path, utils.NewAppError("SFTP_PUT_FAILED", "Failed to write remote file", err))
+		return
+	}
+	c.replyOK(path, "put", nil)
+}
+
+// replyOK marshals result and sends it back as a successful "sftp_result" message.
+func (c *SFTPChannel) replyOK(path, op string, result any) {
+	content := ""
+	if result != nil {
+		if encoded, err := json.Marshal(result); err == nil {
+			content = string(encoded)
+		}
+	}
+	c.conn.WriteJSON(utils.WSMessage{Type: "sftp_result", ChannelID: c.id, Op: op, Path: path, Content: content})
+}
+
+// reply logs appErr and sends it back as an "error" message scoped to this channel.
+func (c *SFTPChannel) reply(path string, appErr *utils.AppError) {
+	appErr.Log()
+	c.conn.WriteJSON(utils.WSMessage{Type: "error", Code: appErr.Code, ChannelID: c.id, Path: path, Content: appErr.Message})
+}
+
+// Close closes the underlying SFTP subsystem.
+func (c *SFTPChannel) Close() {
+	c.client.Close()
+}
@@ -6,23 +6,92 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
+	"time"
 
 	"go-websocket-server/internal/utils"
 
-	"github.com/gorilla/websocket"
 	"golang.org/x/crypto/ssh"
 )
 
+// knownHostsDir is the directory per-user known_hosts files are persisted
+// under. It can be overridden with the KNOWN_HOSTS_DIR environment variable.
+// Session config never chooses this path itself (see sanitizePathComponent)
+// so a client can't direct TOFU-accepted host key writes outside of it.
+var knownHostsDir = func() string {
+	if dir := os.Getenv("KNOWN_HOSTS_DIR"); dir != "" {
+		return dir
+	}
+	return "known_hosts"
+}()
+
+// recordingsDir is the directory cast files are written under. It can be
+// overridden with the RECORDINGS_DIR environment variable. Session config
+// never chooses this path itself, for the same reason as knownHostsDir.
+var recordingsDir = func() string {
+	if dir := os.Getenv("RECORDINGS_DIR"); dir != "" {
+		return dir
+	}
+	return "recordings"
+}()
+
+// sanitizePathComponent reduces s to a single safe path segment: only the
+// last path element is kept and anything in it that isn't alphanumeric, '-',
+// '_' or '.' is replaced, so a value (an SSH username, a client ID) that
+// ultimately comes from the browser can never be used to escape the
+// server-configured directory it's joined onto.
+func sanitizePathComponent(s string) string {
+	s = filepath.Base(s)
+	if s == "" || s == "." || s == ".." {
+		return "_"
+	}
+
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			b.WriteRune(r)
+		default:
+			b.WriteRune('_')
+		}
+	}
+	return b.String()
+}
+
+const (
+	ptyRows = 40
+	ptyCols = 80
+
+	// outputWriteTimeout bounds how long a single output write may block the
+	// WebSocket connection, backpressuring a client that stops reading
+	// rather than letting a slow consumer pin the goroutine forever.
+	outputWriteTimeout = 10 * time.Second
+)
+
 type SSHSession struct {
-	client  *ssh.Client
-	session *ssh.Session
-	stdin   io.WriteCloser
-	stdout  io.Reader
-	stderr  io.Reader
-	conn    *websocket.Conn
-	Done    chan struct{}
-	once    sync.Once
+	client        *ssh.Client
+	session       *ssh.Session
+	stdin         io.WriteCloser
+	stdout        io.Reader
+	stderr        io.Reader
+	conn          *utils.SafeConn
+	recorder      *SessionRecorder
+	inputLimiter  *utils.RateLimiter
+	outputLimiter *utils.RateLimiter
+	Done          chan struct{}
+	once          sync.Once
+}
+
+// SetLimiters installs token-bucket rate limiters policing how much input the
+// session will forward to the SSH server and how much output it will forward
+// to the WebSocket, in bytes/second. Either may be nil to leave that
+// direction unlimited.
+func (s *SSHSession) SetLimiters(input, output *utils.RateLimiter) {
+	s.inputLimiter = input
+	s.outputLimiter = output
 }
 
 // StartSSHSession establishes an SSH session with the specified configuration and WebSocket connection.
@@ -33,18 +102,29 @@ type SSHSession struct {
 // A new SSH session is created and configured with a PTY (pseudo-terminal) request.
 // The session's standard input, output, and error pipes are opened.
 // Finally, the SSHSession struct is initialized with the client, session, pipes, WebSocket connection, and a done channel.
-func StartSSHSession(ctx context.Context, configJSON string, conn *websocket.Conn) (*SSHSession, error) {
+func StartSSHSession(ctx context.Context, clientID, configJSON string, conn *utils.SafeConn) (*SSHSession, error) {
 	var config utils.SSHConfig
 	if err := json.Unmarshal([]byte(configJSON), &config); err != nil {
 		return nil, utils.NewAppError("INVALID_CONFIG", "Invalid configuration format", err)
 	}
 
-	authMethods := utils.GetSSHAuthMethods(config)
+	authMethods := utils.GetSSHAuthMethods(config, conn)
+
+	if err := os.MkdirAll(knownHostsDir, 0o700); err != nil {
+		return nil, utils.NewAppError("KNOWN_HOSTS_DIR_FAILED", "Failed to create known_hosts directory", err)
+	}
+	knownHostsPath := filepath.Join(knownHostsDir, sanitizePathComponent(config.User)+"_known_hosts")
+
+	verifier := NewHostKeyVerifier(knownHostsPath, config.StrictHostKeyChecking, conn)
+	hostKeyCallback, err := verifier.Callback()
+	if err != nil {
+		return nil, err
+	}
 
 	clientConfig := &ssh.ClientConfig{
 		User:            config.User,
 		Auth:            authMethods,
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		HostKeyCallback: hostKeyCallback,
 	}
 
 	address := fmt.Sprintf("%s:%d", config.Host, config.Port)
@@ -81,7 +161,7 @@ func StartSSHSession(ctx context.Context, configJSON string, conn *websocket.Con
 		ssh.TTY_OP_OSPEED: 14400,
 	}
 
-	if err := session.RequestPty("xterm-256color", 40, 80, modes); err != nil {
+	if err := session.RequestPty("xterm-256color", ptyRows, ptyCols, modes); err != nil {
 		client.Close()
 		return nil, utils.NewAppError("PTY_REQUEST_FAILED", "Failed to request PTY", err)
 	}
@@ -91,14 +171,24 @@ func StartSSHSession(ctx context.Context, configJSON string, conn *websocket.Con
 		return nil, utils.NewAppError("SHELL_START_FAILED", "Failed to start shell", err)
 	}
 
+	var recorder *SessionRecorder
+	if config.Record {
+		recorder, err = NewSessionRecorder(recordingsDir, sanitizePathComponent(clientID), ptyCols, ptyRows, config.RecordInput)
+		if err != nil {
+			client.Close()
+			return nil, err
+		}
+	}
+
 	return &SSHSession{
-		client:  client,
-		session: session,
-		stdin:   stdin,
-		stdout:  stdout,
-		stderr:  stderr,
-		conn:    conn,
-		Done:    make(chan struct{}),
+		client:   client,
+		session:  session,
+		stdin:    stdin,
+		stdout:   stdout,
+		stderr:   stderr,
+		conn:     conn,
+		recorder: recorder,
+		Done:     make(chan struct{}),
 	}, nil
 }
 
@@ -131,21 +221,42 @@ func (s *SSHSession) HandleOutput(ctx context.Context) {
 
 				appErr := utils.NewAppError("OUTPUT_READ_FAILED", "Failed to read SSH session output", err)
 				appErr.Log()
-				s.conn.WriteJSON(utils.WSMessage{Type: "error", Content: appErr.Message})
+				s.conn.WriteJSON(utils.WSMessage{Type: "error", Code: appErr.Code, Content: appErr.Message})
 				s.Close()
 				return
 			}
-			s.conn.WriteJSON(utils.WSMessage{Type: "output", Content: string(buf[:n])})
+			if s.recorder != nil {
+				s.recorder.WriteOutput(string(buf[:n]))
+			}
+			s.outputLimiter.Wait(n)
+			s.conn.WriteJSONWithDeadline(time.Now().Add(outputWriteTimeout), utils.WSMessage{Type: "output", Content: string(buf[:n])})
 		}
 	}
 }
 
-// SendInput sends the specified input to the SSH session.
-func (s *SSHSession) SendInput(input string) {
+// Client returns the underlying SSH client, so additional channels (port
+// forwards, SFTP) can be opened on the same connection.
+func (s *SSHSession) Client() *ssh.Client {
+	return s.client
+}
+
+// SendInput sends the specified input to the SSH session. It returns false
+// without writing anything if doing so would exceed the session's input
+// rate limit, so the caller can surface a policy violation to the client.
+func (s *SSHSession) SendInput(input string) bool {
+	if !s.inputLimiter.Allow(len(input)) {
+		return false
+	}
+
 	_, err := s.stdin.Write([]byte(input))
 	if err != nil {
 		log.Printf("Error sending input: %v", err)
+		return true
+	}
+	if s.recorder != nil {
+		s.recorder.WriteInput(input)
 	}
+	return true
 }
 
 // ResizeTerminal resizes the terminal window of the SSH session to the specified number of rows and columns.
@@ -155,6 +266,9 @@ func (s *SSHSession) ResizeTerminal(rows, cols int) error {
 		log.Printf("Error resizing terminal: %v", err)
 		return err
 	}
+	if s.recorder != nil {
+		s.recorder.WriteResize(cols, rows)
+	}
 	return nil
 }
 
@@ -162,6 +276,9 @@ func (s *SSHSession) ResizeTerminal(rows, cols int) error {
 func (s *SSHSession) Close() {
 	s.once.Do(func() {
 		close(s.Done)
+		if s.recorder != nil {
+			s.recorder.Close()
+		}
 		if s.session != nil {
 			s.session.Close()
 		}
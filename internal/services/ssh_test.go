@@ -0,0 +1,25 @@
+package services
+
+import "testing"
+
+func TestSanitizePathComponent(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{"alice", "alice"},
+		{"", "_"},
+		{".", "_"},
+		{"..", "_"},
+		{"../../etc/passwd", "passwd"},
+		{"/etc/cron.d", "cron.d"},
+		{"..\\..\\windows", ".._.._windows"},
+		{"weird name;rm -rf", "weird_name_rm_-rf"},
+	}
+
+	for _, c := range cases {
+		if got := sanitizePathComponent(c.in); got != c.want {
+			t.Errorf("sanitizePathComponent(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+}
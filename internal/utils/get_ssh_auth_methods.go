@@ -2,30 +2,60 @@ package utils
 
 import (
 	"fmt"
+	"net"
+	"os"
 
 	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
 )
 
+// SSHConfig is client-supplied, so it deliberately has no knobs for where
+// known_hosts files or session recordings land on disk: those paths are
+// always confined to the server-configured KNOWN_HOSTS_DIR/RECORDINGS_DIR
+// (see services.StartSSHSession), keyed off the authenticated user/client
+// rather than anything the browser can choose.
 type SSHConfig struct {
-	Host       string `json:"host"`
-	Port       int    `json:"port"`
-	User       string `json:"user"`
-	Password   string `json:"password,omitempty"`
-	PrivateKey string `json:"privateKey,omitempty"`
+	Host                  string `json:"host"`
+	Port                  int    `json:"port"`
+	User                  string `json:"user"`
+	Password              string `json:"password,omitempty"`
+	PrivateKey            string `json:"privateKey,omitempty"`
+	StrictHostKeyChecking bool   `json:"strictHostKeyChecking,omitempty"`
+	UseAgent              bool   `json:"useAgent,omitempty"`
+	AgentSocket           string `json:"agentSocket,omitempty"`
+	Interactive           bool   `json:"interactive,omitempty"`
+	Record                bool   `json:"record,omitempty"`
+	RecordInput           bool   `json:"recordInput,omitempty"`
 }
 
 type WSMessage struct {
-	Type    string `json:"type"`
-	Content string `json:"content,omitempty"`
-	Rows    int    `json:"rows,omitempty"`
-	Cols    int    `json:"cols,omitempty"`
+	Type        string   `json:"type"`
+	Code        string   `json:"code,omitempty"`
+	Content     string   `json:"content,omitempty"`
+	Rows        int      `json:"rows,omitempty"`
+	Cols        int      `json:"cols,omitempty"`
+	Host        string   `json:"host,omitempty"`
+	Fingerprint string   `json:"fingerprint,omitempty"`
+	Questions   []string `json:"questions,omitempty"`
+	Answers     []string `json:"answers,omitempty"`
+	ChannelID   string   `json:"channelId,omitempty"`
+	Op          string   `json:"op,omitempty"`
+	TargetHost  string   `json:"targetHost,omitempty"`
+	TargetPort  int      `json:"targetPort,omitempty"`
+	BindHost    string   `json:"bindHost,omitempty"`
+	BindPort    int      `json:"bindPort,omitempty"`
+	Path        string   `json:"path,omitempty"`
+	Data        string   `json:"data,omitempty"`
 }
 
 // GetSSHAuthMethods returns a slice of ssh.AuthMethod based on the provided SSHConfig.
-// It supports password authentication and private key authentication with or without a passphrase.
+// It supports password authentication, private key authentication with or without a
+// passphrase, SSH agent authentication, and keyboard-interactive authentication.
 //
 // Parameters:
 //   - config: SSHConfig containing the authentication details.
+//   - conn: the WebSocket connection used to round-trip keyboard-interactive
+//     challenges to the browser client when config.Interactive is set.
 //
 // Returns:
 //   - []ssh.AuthMethod: A slice of ssh.AuthMethod to be used for SSH authentication.
@@ -33,7 +63,10 @@ type WSMessage struct {
 // If a password is provided in the config, it will be used for password authentication.
 // If a private key is provided, it will be parsed and used for public key authentication.
 // If both a private key and a password are provided, the private key will be parsed with the passphrase.
-func GetSSHAuthMethods(config SSHConfig) []ssh.AuthMethod {
+// If UseAgent is set, keys are sourced from the SSH agent listening on AgentSocket
+// (or $SSH_AUTH_SOCK). If Interactive is set, keyboard-interactive challenges are
+// pumped to the client as "auth_challenge" messages and answered via "auth_response".
+func GetSSHAuthMethods(config SSHConfig, conn *SafeConn) []ssh.AuthMethod {
 	authMethods := []ssh.AuthMethod{}
 	if config.Password != "" {
 		authMethods = append(authMethods, ssh.Password(config.Password))
@@ -58,5 +91,56 @@ func GetSSHAuthMethods(config SSHConfig) []ssh.AuthMethod {
 
 		authMethods = append(authMethods, ssh.PublicKeys(key))
 	}
+	if config.UseAgent {
+		if signers, err := agentSigners(config.AgentSocket); err != nil {
+			fmt.Printf("Error connecting to SSH agent: %v\n", err)
+		} else {
+			authMethods = append(authMethods, ssh.PublicKeysCallback(signers))
+		}
+	}
+	if config.Interactive && conn != nil {
+		authMethods = append(authMethods, ssh.KeyboardInteractive(keyboardInteractiveChallenge(conn)))
+	}
 	return authMethods
 }
+
+// agentSigners dials the SSH agent listening on socketPath (or $SSH_AUTH_SOCK
+// when empty) and returns a callback that lists the keys it holds.
+func agentSigners(socketPath string) (func() ([]ssh.Signer, error), error) {
+	if socketPath == "" {
+		socketPath = os.Getenv("SSH_AUTH_SOCK")
+	}
+	if socketPath == "" {
+		return nil, fmt.Errorf("no agent socket configured and SSH_AUTH_SOCK is unset")
+	}
+
+	conn, err := net.Dial("unix", socketPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial SSH agent socket: %w", err)
+	}
+
+	agentClient := agent.NewClient(conn)
+	return agentClient.Signers, nil
+}
+
+// keyboardInteractiveChallenge returns an ssh.KeyboardInteractiveChallenge that
+// forwards each challenge's questions to the browser client as an
+// "auth_challenge" WSMessage and blocks until the client replies with the
+// matching "auth_response" message.
+func keyboardInteractiveChallenge(conn *SafeConn) ssh.KeyboardInteractiveChallenge {
+	return func(name, instruction string, questions []string, echos []bool) ([]string, error) {
+		if err := conn.WriteJSON(WSMessage{Type: "auth_challenge", Content: instruction, Questions: questions}); err != nil {
+			return nil, fmt.Errorf("failed to send auth challenge: %w", err)
+		}
+
+		var reply WSMessage
+		if err := conn.ReadJSON(&reply); err != nil {
+			return nil, fmt.Errorf("failed to read auth response: %w", err)
+		}
+		if reply.Type != "auth_response" {
+			return nil, fmt.Errorf("expected auth_response, got %q", reply.Type)
+		}
+
+		return reply.Answers, nil
+	}
+}
@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"sync"
+	"time"
+)
+
+// RateLimiter is a simple token bucket used to cap bytes/second of input or
+// output for a single connection. It bursts up to one second's worth of
+// tokens and refills continuously based on wall-clock elapsed time.
+type RateLimiter struct {
+	mu         sync.Mutex
+	tokens     float64
+	max        float64
+	refillRate float64
+	last       time.Time
+}
+
+// NewRateLimiter creates a limiter that allows up to bytesPerSecond bytes per
+// second. A non-positive bytesPerSecond disables the limit entirely.
+func NewRateLimiter(bytesPerSecond int) *RateLimiter {
+	rate := float64(bytesPerSecond)
+	return &RateLimiter{tokens: rate, max: rate, refillRate: rate, last: time.Now()}
+}
+
+func (r *RateLimiter) refill() {
+	now := time.Now()
+	elapsed := now.Sub(r.last).Seconds()
+	r.last = now
+	r.tokens += elapsed * r.refillRate
+	if r.tokens > r.max {
+		r.tokens = r.max
+	}
+}
+
+// Allow reports whether n bytes may be sent right now, consuming tokens if so.
+func (r *RateLimiter) Allow(n int) bool {
+	if r == nil || r.max <= 0 {
+		return true
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.refill()
+	if r.tokens < float64(n) {
+		return false
+	}
+	r.tokens -= float64(n)
+	return true
+}
+
+// Wait blocks until n bytes worth of tokens are available, then consumes
+// them. Used to backpressure output instead of dropping it. n is capped to
+// the bucket's burst size first: tokens never refill past max, so without
+// this a single chunk larger than max (a full read buffer against a tight
+// limit, say) would never be "allowed" and Wait would block forever.
+func (r *RateLimiter) Wait(n int) {
+	if r == nil || r.max <= 0 {
+		return
+	}
+
+	if float64(n) > r.max {
+		n = int(r.max)
+	}
+
+	for !r.Allow(n) {
+		time.Sleep(10 * time.Millisecond)
+	}
+}
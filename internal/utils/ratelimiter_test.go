@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiterAllowWithinBudget(t *testing.T) {
+	r := NewRateLimiter(100)
+	if !r.Allow(50) {
+		t.Error("Allow(50) = false, want true for a fresh 100 B/s bucket")
+	}
+	if !r.Allow(50) {
+		t.Error("Allow(50) = false, want true when exactly draining the remaining burst")
+	}
+}
+
+func TestRateLimiterAllowOverBudget(t *testing.T) {
+	r := NewRateLimiter(100)
+	if r.Allow(150) {
+		t.Error("Allow(150) = true, want false when exceeding the bucket's burst size")
+	}
+}
+
+func TestRateLimiterAllowDisabledWhenNonPositive(t *testing.T) {
+	r := NewRateLimiter(0)
+	if !r.Allow(1_000_000) {
+		t.Error("Allow() = false, want true when bytesPerSecond is non-positive (unlimited)")
+	}
+}
+
+func TestRateLimiterAllowNilReceiverIsUnlimited(t *testing.T) {
+	var r *RateLimiter
+	if !r.Allow(1_000_000) {
+		t.Error("Allow() on a nil *RateLimiter = false, want true")
+	}
+	r.Wait(1_000_000)
+}
+
+// TestRateLimiterWaitOverBurstDoesNotDeadlock guards against a chunk larger
+// than the bucket's burst size (e.g. a 4096-byte read against a tighter
+// configured limit) hanging Wait forever: tokens never refill past max, so
+// Wait must cap what it waits for to the bucket's own size instead of the
+// full request.
+func TestRateLimiterWaitOverBurstDoesNotDeadlock(t *testing.T) {
+	r := NewRateLimiter(100)
+
+	done := make(chan struct{})
+	go func() {
+		r.Wait(4096)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Wait(4096) against a 100 B/s bucket did not return, want it to cap to the burst size and proceed")
+	}
+}
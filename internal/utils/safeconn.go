@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// SafeConn wraps a *websocket.Conn so that every writer goroutine spawned
+// for a session (shell output, port forwards, SFTP replies, the policy
+// monitor, ...) can call WriteJSON without racing: gorilla/websocket only
+// allows one concurrent writer per connection. Reads are left unguarded
+// since each connection only ever has a single reader, the session's main
+// dispatch loop.
+type SafeConn struct {
+	conn *websocket.Conn
+	mu   sync.Mutex
+}
+
+// NewSafeConn wraps conn for concurrent-safe writes.
+func NewSafeConn(conn *websocket.Conn) *SafeConn {
+	return &SafeConn{conn: conn}
+}
+
+// WriteJSON writes v to the connection as JSON, serialized against every
+// other writer sharing this SafeConn. The write deadline is cleared first,
+// so a deadline left behind by a prior WriteJSONWithDeadline call can never
+// cause this write to fail outright before it even reaches the wire.
+func (c *SafeConn) WriteJSON(v any) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.conn.SetWriteDeadline(time.Time{})
+	return c.conn.WriteJSON(v)
+}
+
+// WriteJSONWithDeadline is WriteJSON, but bounds the write by deadline while
+// still holding the write lock, so the deadline can't be clobbered by a
+// concurrent writer's own deadline before this write goes out. The deadline
+// is cleared again immediately after, since gorilla/websocket leaves a timed
+// out connection's write deadline in place otherwise: once that deadline is
+// in the past, every subsequent write — including plain WriteJSON calls that
+// never asked for a deadline — would fail instantly.
+func (c *SafeConn) WriteJSONWithDeadline(deadline time.Time, v any) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	defer c.conn.SetWriteDeadline(time.Time{})
+	if err := c.conn.SetWriteDeadline(deadline); err != nil {
+		return err
+	}
+	return c.conn.WriteJSON(v)
+}
+
+// ReadJSON reads the next JSON message from the connection into v.
+func (c *SafeConn) ReadJSON(v any) error {
+	return c.conn.ReadJSON(v)
+}
+
+// Close closes the underlying connection.
+func (c *SafeConn) Close() error {
+	return c.conn.Close()
+}